@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+)
+
+// Config describes the operator-supplied overrides loaded from -config.file.
+// It lets heterogeneous clusters suppress metrics or devices that don't
+// apply everywhere (e.g. fan_speed on passively cooled datacenter cards)
+// without rebuilding the exporter.
+type Config struct {
+	// ExcludeMetrics lists gauge names (matching the keys in gaugeMetrics /
+	// memoryMetrics) that should not be registered or collected.
+	ExcludeMetrics []string `json:"exclude_metrics"`
+	// ExcludeDevices lists devices, by UUID or index, to skip during collection.
+	ExcludeDevices []string `json:"exclude_devices"`
+	// AddPCIInfoTag adds a pci_busid label, sourced from nvmlDeviceGetPciInfo,
+	// to every gauge.
+	AddPCIInfoTag bool `json:"add_pci_info_tag"`
+}
+
+// loadConfig reads and parses the JSON config at path. An empty path returns
+// the zero-value Config, so -config.file is optional.
+func loadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: reading %s: %s", path, err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("config: parsing %s: %s", path, err)
+	}
+
+	return cfg, nil
+}
+
+// excludesMetric reports whether name appears in ExcludeMetrics
+func (c *Config) excludesMetric(name string) bool {
+	for _, m := range c.ExcludeMetrics {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// excludesDevice reports whether the device at idx, identified by UUID or index, appears in ExcludeDevices
+func (c *Config) excludesDevice(idx int, device Device) bool {
+	id := strconv.Itoa(idx)
+	for _, d := range c.ExcludeDevices {
+		if d == device.DeviceUUID || d == id {
+			return true
+		}
+	}
+	return false
+}
@@ -11,10 +11,13 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-// VecInfo stores the prometheus help and labels to
+// VecInfo stores a gauge's prometheus help text and any labels it carries
+// beyond the standard device_id/device_uuid/device_name (and, when
+// -config.file sets add_pci_info_tag, pci_busid) that baseLabelNames/
+// baseLabelValues attach to every gauge.
 type VecInfo struct {
-	help   string
-	labels []string
+	help        string
+	extraLabels []string
 }
 
 var (
@@ -23,62 +26,138 @@ var (
 	// unexported variables below
 	listenAddress string
 	metricsPath   string
+	configFile    string
+	migProcess    bool
+	migUseUUID    bool
+	addPCIInfoTag bool
+
+	// memoryMetrics describes the gauges that report per-instance data when
+	// MIG mode is active, so their label sets are built at runtime once
+	// -mig.process and -mig.use-uuid have been parsed. See NewExporter.
+	memoryMetrics = map[string]string{
+		"memory_free":    "Number of bytes free in the GPU Memory",
+		"memory_total":   "Total bytes of the GPU's memory",
+		"memory_used":    "Total number of bytes used in the GPU Memory",
+		"memory_percent": "Percent of GPU Memory Utilized",
+	}
 
 	gaugeMetrics = map[string]*VecInfo{
 		"power_watts": &VecInfo{
-			help:   "Power Usage of an NVIDIA GPU in Watts",
-			labels: []string{"device_id", "device_uuid", "device_name"},
+			help: "Power Usage of an NVIDIA GPU in Watts",
 		},
 		"fan_speed": &VecInfo{
-                        help:   "Device Fan Speed in Percent of Maximum",
-                        labels: []string{"device_id", "device_uuid", "device_name"},
-                },
+			help: "Device Fan Speed in Percent of Maximum",
+		},
 		"gpu_percent": &VecInfo{
-			help:   "Percent of GPU Utilized",
-			labels: []string{"device_id", "device_uuid", "device_name"},
+			help: "Percent of GPU Utilized",
 		},
-		"memory_free": &VecInfo{
-			help:   "Number of bytes free in the GPU Memory",
-			labels: []string{"device_id", "device_uuid", "device_name"},
+		"temperature_fahrenheit": &VecInfo{
+			help: "GPU Temperature in Fahrenheit",
 		},
-		"memory_total": &VecInfo{
-			help:   "Total bytes of the GPU's memory",
-			labels: []string{"device_id", "device_uuid", "device_name"},
+		"temperature_celsius": &VecInfo{
+			help: "GPU Temperature in Celsius",
 		},
-		"memory_used": &VecInfo{
-			help:   "Total number of bytes used in the GPU Memory",
-			labels: []string{"device_id", "device_uuid", "device_name"},
+		"nvlink_rx_bytes": &VecInfo{
+			help:        "NVLink RX bytes accumulated on a given link",
+			extraLabels: []string{"link"},
 		},
-		"memory_percent": &VecInfo{
-			help:   "Percent of GPU Memory Utilized",
-			labels: []string{"device_id", "device_uuid", "device_name"},
+		"nvlink_tx_bytes": &VecInfo{
+			help:        "NVLink TX bytes accumulated on a given link",
+			extraLabels: []string{"link"},
 		},
-		"temperature_fahrenheit": &VecInfo{
-			help:   "GPU Temperature in Fahrenheit",
-			labels: []string{"device_id", "device_uuid", "device_name"},
+		"pcie_tx_bytes": &VecInfo{
+			help: "PCIe TX throughput in bytes/sec, sampled over a 20ms interval",
 		},
-		"temperature_celsius": &VecInfo{
-			help:   "GPU Temperature in Celsius",
-			labels: []string{"device_id", "device_uuid", "device_name"},
+		"pcie_rx_bytes": &VecInfo{
+			help: "PCIe RX throughput in bytes/sec, sampled over a 20ms interval",
+		},
+		"clock_graphics_mhz": &VecInfo{
+			help: "Graphics clock speed in MHz",
+		},
+		"clock_sm_mhz": &VecInfo{
+			help: "SM clock speed in MHz",
+		},
+		"clock_memory_mhz": &VecInfo{
+			help: "Memory clock speed in MHz",
+		},
+		"ecc_errors_corrected_volatile": &VecInfo{
+			help: "Corrected ECC errors since the last driver load",
+		},
+		"ecc_errors_uncorrected_volatile": &VecInfo{
+			help: "Uncorrected ECC errors since the last driver load",
+		},
+		"ecc_errors_corrected_aggregate": &VecInfo{
+			help: "Corrected ECC errors over the lifetime of the device",
+		},
+		"ecc_errors_uncorrected_aggregate": &VecInfo{
+			help: "Uncorrected ECC errors over the lifetime of the device",
+		},
+		"performance_state": &VecInfo{
+			help: "Current P-state of the GPU, where 0 is the highest performance state",
+		},
+		"power_limit_watts": &VecInfo{
+			help: "Power limit enforced by the driver in Watts",
+		},
+		"process_memory_bytes": &VecInfo{
+			help:        "GPU memory used by a process with an active context on the device",
+			extraLabels: []string{"pid", "process_name"},
 		},
 	}
 )
 
+// baseLabelNames returns the label names every per-device gauge carries:
+// device_id/device_uuid/device_name, plus pci_busid when cfg.AddPCIInfoTag is set.
+func baseLabelNames(cfg *Config) []string {
+	labels := []string{"device_id", "device_uuid", "device_name"}
+	if cfg.AddPCIInfoTag {
+		labels = append(labels, "pci_busid")
+	}
+	return labels
+}
+
+// baseLabelValues returns the label values matching baseLabelNames(e.cfg) for a given device.
+func (e *Exporter) baseLabelValues(id string, device Device) []string {
+	vals := []string{id, device.DeviceUUID, device.DeviceName}
+	if e.cfg.AddPCIInfoTag {
+		vals = append(vals, device.PCIBusID)
+	}
+	return vals
+}
+
+// setGauge records value against the named gauge, if it was registered; it
+// is a no-op for metrics the operator excluded via -config.file.
+func (e *Exporter) setGauge(name string, value float64, labelValues ...string) {
+	vec, ok := e.gauges[name]
+	if !ok {
+		return
+	}
+	vec.WithLabelValues(labelValues...).Set(value)
+}
+
 // Exporter TODO
 type Exporter struct {
 	mutex sync.RWMutex
 
-	up     prometheus.Gauge
-	gauges map[string]*prometheus.GaugeVec
+	up       prometheus.Gauge
+	deviceUp *prometheus.GaugeVec
+	gauges   map[string]*prometheus.GaugeVec
 
-	devices []Device
+	devices       []Device
+	cfg           *Config
+	nvmlAvailable bool
 }
 
-// NewExporter TODO
-func NewExporter() (*Exporter, error) {
-	devices, err := GetDevices()
-	if err != nil {
-		return nil, err
+// NewExporter builds an Exporter registered against cfg. When nvmlAvailable
+// is false (InitNVML failed, e.g. no driver or a -nocgo build), it skips
+// device enumeration entirely; Collect then retries InitNVML on each scrape
+// and reports nvml_up=0, without querying devices, until that succeeds.
+func NewExporter(cfg *Config, nvmlAvailable bool) (*Exporter, error) {
+	var devices []Device
+	if nvmlAvailable {
+		var err error
+		if devices, err = GetDevices(); err != nil {
+			return nil, err
+		}
 	}
 
 	exp := &Exporter{
@@ -88,78 +167,342 @@ func NewExporter() (*Exporter, error) {
 			Name:      "up",
 			Help:      "Were the NVML queries successful?",
 		}),
-		devices: devices,
+		deviceUp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: DefaultNamespace,
+			Name:      "device_up",
+			Help:      "Whether the last scrape successfully queried this device (1) or hit an error partway through (0)",
+		}, []string{"device_id"}),
+		devices:       devices,
+		cfg:           cfg,
+		nvmlAvailable: nvmlAvailable,
 	}
 
+	baseLabels := baseLabelNames(cfg)
+
 	for name, info := range gaugeMetrics {
+		if cfg.excludesMetric(name) {
+			continue
+		}
 		exp.gauges[name] = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Namespace: DefaultNamespace,
 			Name:      name,
 			Help:      info.help,
-		}, info.labels)
+		}, append(append([]string{}, baseLabels...), info.extraLabels...))
+	}
+
+	memoryLabels := append(append([]string{}, baseLabels...), migLabels()...)
+	for name, help := range memoryMetrics {
+		if cfg.excludesMetric(name) {
+			continue
+		}
+		exp.gauges[name] = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: DefaultNamespace,
+			Name:      name,
+			Help:      help,
+		}, memoryLabels)
 	}
 
 	return exp, nil
 }
 
+// migLabels returns the extra label names MIG-aware gauges should carry,
+// based on -mig.process and -mig.use-uuid. It is empty when MIG enumeration
+// is disabled, so non-MIG deployments see no change in exported labels.
+func migLabels() []string {
+	if !migProcess {
+		return nil
+	}
+	if migUseUUID {
+		return []string{"mig_uuid"}
+	}
+	return []string{"gpu_instance_id", "compute_instance_id"}
+}
+
+// migLabelValues returns the MIG label values to pair with migLabels() for a
+// given instance. migDevice is nil when reporting a device that has no MIG
+// instance of its own (either MIG is off, or this row is the parent device).
+func migLabelValues(migDevice *Device) []string {
+	if !migProcess {
+		return nil
+	}
+	if migUseUUID {
+		if migDevice == nil {
+			return []string{""}
+		}
+		return []string{migDevice.DeviceUUID}
+	}
+	if migDevice == nil {
+		return []string{"-1", "-1"}
+	}
+	return []string{strconv.Itoa(migDevice.GPUInstanceID), strconv.Itoa(migDevice.ComputeInstanceID)}
+}
+
 // Describe describes all the metrics ever exported by the nvml/nvidia exporter.
 // It implements prometheus.Collector.
 func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
 	ch <- e.up.Desc()
+	e.deviceUp.Describe(ch)
 
 	for _, vec := range e.gauges {
 		vec.Describe(ch)
 	}
 }
 
-// GetTelemetryFromNVML collects device telemetry from all NVIDIA GPUs connected to this machine
+// deviceCollector fetches one logical group of NVML metrics for a device and
+// records them directly into the exporter's gauges.
+type deviceCollector func(e *Exporter, id string, device Device) error
+
+// deviceCollectors lists every metric group GetTelemetryFromNVML runs per
+// device, in order. Adding a new NVML-backed metric means adding a collector
+// here rather than growing a hard-coded chain of queries.
+var deviceCollectors = []deviceCollector{
+	collectUtilizationAndMemory,
+	collectTemperature,
+	collectPower,
+	collectFanSpeed,
+	collectNvLink,
+	collectPcieThroughput,
+	collectClocks,
+	collectEccErrors,
+	collectPerformanceState,
+	collectPowerLimit,
+	collectProcesses,
+}
+
+// GetTelemetryFromNVML collects device telemetry from all NVIDIA GPUs
+// connected to this machine. A failure on one device is reported on that
+// device's nvml_device_up series and skips the rest of its collectors, but
+// doesn't stop the other devices from being scraped. If any error looks like
+// a driver reset (NVML_ERROR_UNINITIALIZED/NVML_ERROR_GPU_IS_LOST), NVML is
+// reinitialized and the device list rebuilt before the next scrape.
 func (e *Exporter) GetTelemetryFromNVML() {
-	var (
-		gpuMem                    *NVMLMemory
-		powerUsage                int
-		fanSpeed		  int
-		gpuPercent, memoryPercent int
-		err                       error
-		tempF, tempC              int
-	)
+	reinit := false
 
 	for idx, device := range e.devices {
+		if e.cfg.excludesDevice(idx, device) {
+			continue
+		}
+
 		id := strconv.Itoa(idx)
-		if gpuPercent, memoryPercent, err = device.GetUtilization(); err != nil {
-			goto ErrorFetching
+
+		deviceUp := 1.0
+		for _, collect := range deviceCollectors {
+			if err := collect(e, id, device); err != nil {
+				log.Printf("Failed to query device %s: %s\n", device.DeviceUUID, err.Error())
+				deviceUp = 0
+				if nvmlNeedsReinit(err) {
+					reinit = true
+				}
+				break
+			}
 		}
-		e.gauges["gpu_percent"].WithLabelValues(id, device.DeviceUUID, device.DeviceName).Set(float64(gpuPercent))
-		e.gauges["memory_percent"].WithLabelValues(id, device.DeviceUUID, device.DeviceName).Set(float64(memoryPercent))
+		e.deviceUp.WithLabelValues(id).Set(deviceUp)
+	}
+
+	if reinit {
+		e.reinitNVML()
+	}
+}
+
+// reinitNVML tears down and recreates NVML state and re-enumerates devices.
+// It's called when a query reports NVML_ERROR_UNINITIALIZED or
+// NVML_ERROR_GPU_IS_LOST, which NVML returns after a driver reset
+// (nvidia-smi -r) or a GPU dropping off the bus; the stale device list from
+// before the reset would otherwise keep failing every scrape.
+func (e *Exporter) reinitNVML() {
+	log.Println("NVML state looks stale (driver reset?); reinitializing and re-enumerating devices")
 
-		if tempF, tempC, err = device.GetTemperature(); err != nil {
-			goto ErrorFetching
+	if err := ShutdownNVML(); err != nil {
+		log.Printf("Failed shutting down NVML during reinit: %s\n", err.Error())
+	}
+
+	if err := InitNVML(); err != nil {
+		log.Printf("Failed reinitializing NVML: %s\n", err.Error())
+		e.nvmlAvailable = false
+		return
+	}
+
+	devices, err := GetDevices()
+	if err != nil {
+		log.Printf("Failed re-enumerating devices after NVML reinit: %s\n", err.Error())
+		return
+	}
+	e.devices = devices
+}
+
+func collectTemperature(e *Exporter, id string, device Device) error {
+	tempF, tempC, err := device.GetTemperature()
+	if err != nil {
+		return err
+	}
+	e.setGauge("temperature_celsius", float64(tempC), e.baseLabelValues(id, device)...)
+	e.setGauge("temperature_fahrenheit", float64(tempF), e.baseLabelValues(id, device)...)
+	return nil
+}
+
+func collectPower(e *Exporter, id string, device Device) error {
+	usage, err := device.GetPowerUsage()
+	if err != nil {
+		return err
+	}
+	e.setGauge("power_watts", float64(usage), e.baseLabelValues(id, device)...)
+	return nil
+}
+
+func collectFanSpeed(e *Exporter, id string, device Device) error {
+	speed, err := device.GetFanSpeed()
+	if err != nil {
+		return err
+	}
+	e.setGauge("fan_speed", float64(speed), e.baseLabelValues(id, device)...)
+	return nil
+}
+
+// collectUtilizationAndMemory reports gpu_percent alongside memory: both come
+// off the same nvmlDeviceGetUtilizationRates call, so querying them together
+// avoids a second NVML round-trip per device per scrape for the memory
+// percentage. Memory itself is reported per parent device when MIG isn't in
+// play, or one series per MIG instance when it is; memory is the one metric
+// family MIG slices support on their own, unlike utilization/power/fan/temp.
+func collectUtilizationAndMemory(e *Exporter, id string, device Device) error {
+	gpuPercent, memoryPercent, err := device.GetUtilization()
+	if err != nil {
+		return err
+	}
+	e.setGauge("gpu_percent", float64(gpuPercent), e.baseLabelValues(id, device)...)
+
+	if migProcess && device.MIGEnabled && len(device.MIGDevices) > 0 {
+		for i := range device.MIGDevices {
+			migDev := &device.MIGDevices[i]
+			mem, err := migDev.GetMemoryInfo()
+			if err != nil {
+				return err
+			}
+			e.setMemoryGauges(id, device, migDev, &mem, -1)
+		}
+		return nil
+	}
+
+	mem, err := device.GetMemoryInfo()
+	if err != nil {
+		return err
+	}
+	e.setMemoryGauges(id, device, nil, &mem, memoryPercent)
+	return nil
+}
+
+func collectNvLink(e *Exporter, id string, device Device) error {
+	for link := 0; link < maxNvLinks; link++ {
+		active, err := device.GetNvLinkState(link)
+		if err != nil || !active {
+			// Most devices don't populate every link index; treat both a
+			// query error and an inactive link as "nothing to report".
+			continue
 		}
-		e.gauges["temperature_celsius"].WithLabelValues(id, device.DeviceUUID, device.DeviceName).Set(float64(tempC))
-		e.gauges["temperature_fahrenheit"].WithLabelValues(id, device.DeviceUUID, device.DeviceName).Set(float64(tempF))
 
-		if powerUsage, err = device.GetPowerUsage(); err != nil {
-			goto ErrorFetching
+		util, err := device.GetNvLinkUtilizationCounter(link)
+		if err != nil {
+			return err
 		}
-		e.gauges["power_watts"].WithLabelValues(id, device.DeviceUUID, device.DeviceName).Set(float64(powerUsage))
 
-		if fanSpeed, err = device.GetFanSpeed(); err != nil {
-                        goto ErrorFetching
-                }
-		e.gauges["fan_speed"].WithLabelValues(id, device.DeviceUUID, device.DeviceName).Set(float64(fanSpeed))
+		linkID := strconv.Itoa(link)
+		labels := append(e.baseLabelValues(id, device), linkID)
+		e.setGauge("nvlink_rx_bytes", float64(util.RxBytes), labels...)
+		e.setGauge("nvlink_tx_bytes", float64(util.TxBytes), labels...)
+	}
+	return nil
+}
+
+func collectPcieThroughput(e *Exporter, id string, device Device) error {
+	t, err := device.GetPcieThroughput()
+	if err != nil {
+		return err
+	}
+	// nvmlDeviceGetPcieThroughput reports KB/s.. convert to bytes/s
+	e.setGauge("pcie_tx_bytes", float64(t.TxKB)*1024, e.baseLabelValues(id, device)...)
+	e.setGauge("pcie_rx_bytes", float64(t.RxKB)*1024, e.baseLabelValues(id, device)...)
+	return nil
+}
 
-		if gpuMem, err = device.GetMemoryInfo(); err != nil {
-			goto ErrorFetching
+func collectClocks(e *Exporter, id string, device Device) error {
+	c, err := device.GetClockSpeeds()
+	if err != nil {
+		return err
+	}
+	e.setGauge("clock_graphics_mhz", float64(c.Graphics), e.baseLabelValues(id, device)...)
+	e.setGauge("clock_sm_mhz", float64(c.SM), e.baseLabelValues(id, device)...)
+	e.setGauge("clock_memory_mhz", float64(c.Memory), e.baseLabelValues(id, device)...)
+	return nil
+}
+
+func collectEccErrors(e *Exporter, id string, device Device) error {
+	counts, err := device.GetEccErrors()
+	if err != nil {
+		return err
+	}
+	e.setGauge("ecc_errors_corrected_volatile", float64(counts.CorrectedVolatile), e.baseLabelValues(id, device)...)
+	e.setGauge("ecc_errors_uncorrected_volatile", float64(counts.UncorrectedVolatile), e.baseLabelValues(id, device)...)
+	e.setGauge("ecc_errors_corrected_aggregate", float64(counts.CorrectedAggregate), e.baseLabelValues(id, device)...)
+	e.setGauge("ecc_errors_uncorrected_aggregate", float64(counts.UncorrectedAggregate), e.baseLabelValues(id, device)...)
+	return nil
+}
+
+func collectPerformanceState(e *Exporter, id string, device Device) error {
+	state, err := device.GetPerformanceState()
+	if err != nil {
+		return err
+	}
+	e.setGauge("performance_state", float64(state), e.baseLabelValues(id, device)...)
+	return nil
+}
+
+func collectPowerLimit(e *Exporter, id string, device Device) error {
+	limit, err := device.GetPowerManagementLimit()
+	if err != nil {
+		return err
+	}
+	e.setGauge("power_limit_watts", float64(limit), e.baseLabelValues(id, device)...)
+	return nil
+}
+
+func collectProcesses(e *Exporter, id string, device Device) error {
+	computeProcs, err := device.GetComputeRunningProcesses()
+	if err != nil {
+		return err
+	}
+	graphicsProcs, err := device.GetGraphicsRunningProcesses()
+	if err != nil {
+		return err
+	}
+
+	for _, proc := range append(computeProcs, graphicsProcs...) {
+		labels := append(e.baseLabelValues(id, device), strconv.Itoa(int(proc.PID)), proc.Name)
+		e.setGauge("process_memory_bytes", float64(proc.UsedMemory), labels...)
+	}
+	return nil
+}
+
+// setMemoryGauges records the memory_* gauges for a device, or one of its MIG
+// instances when migDev is non-nil, under the base device labels plus
+// whatever migLabelValues(migDev) contributes. utilMemoryPercent is the
+// value reported by nvmlDeviceGetUtilizationRates and is only meaningful for
+// the parent device; MIG instances don't support that call, so their
+// memory_percent is derived from used/total instead.
+func (e *Exporter) setMemoryGauges(id string, device Device, migDev *Device, mem *NVMLMemory, utilMemoryPercent int) {
+	labels := append(e.baseLabelValues(id, device), migLabelValues(migDev)...)
+
+	memoryPercent := float64(utilMemoryPercent)
+	if migDev != nil {
+		memoryPercent = 0
+		if mem.Total > 0 {
+			memoryPercent = float64(mem.Used) / float64(mem.Total) * 100
 		}
-		e.gauges["memory_free"].WithLabelValues(id, device.DeviceUUID, device.DeviceName).Set(float64(gpuMem.Free))
-		e.gauges["memory_total"].WithLabelValues(id, device.DeviceUUID, device.DeviceName).Set(float64(gpuMem.Total))
-		e.gauges["memory_used"].WithLabelValues(id, device.DeviceUUID, device.DeviceName).Set(float64(gpuMem.Used))
-		continue
-
-	ErrorFetching:
-		log.Printf("Failed to query device %s: %s\n", device.DeviceUUID, err.Error())
-		e.up.Set(0)
-		return
 	}
+
+	e.setGauge("memory_free", float64(mem.Free), labels...)
+	e.setGauge("memory_total", float64(mem.Total), labels...)
+	e.setGauge("memory_used", float64(mem.Used), labels...)
+	e.setGauge("memory_percent", memoryPercent, labels...)
 }
 
 // Collect grabs the telemetry data from this machine using NVIDIA's Management Library.
@@ -171,10 +514,36 @@ func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
 	for _, vec := range e.gauges {
 		vec.Reset()
 	}
+	e.deviceUp.Reset()
 
 	defer func() { ch <- e.up }()
+	defer e.deviceUp.Collect(ch)
+
+	if !e.nvmlAvailable {
+		// A previous reinit attempt failed (e.g. the driver hadn't finished
+		// reloading yet). Retry InitNVML here rather than latching this
+		// scrape's failure forever: reinitNVML() is only ever reached via
+		// GetTelemetryFromNVML below, so if we returned early every scrape
+		// would permanently report nvml_up=0 after a single failed attempt.
+		if err := InitNVML(); err != nil {
+			log.Printf("NVML still unavailable: %s\n", err.Error())
+			e.up.Set(0)
+			return
+		}
+
+		devices, err := GetDevices()
+		if err != nil {
+			log.Printf("Failed enumerating devices after NVML became available: %s\n", err.Error())
+			e.up.Set(0)
+			return
+		}
+		e.devices = devices
+		e.nvmlAvailable = true
+	}
 
-	// If we fail at any point in retrieving GPU status, we fail 0
+	// up reflects whether we could talk to NVML at all this scrape; a
+	// single device's errors are reported on nvml_device_up instead, since
+	// GetTelemetryFromNVML no longer aborts the whole scrape on one.
 	e.up.Set(1)
 
 	e.GetTelemetryFromNVML()
@@ -187,14 +556,29 @@ func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
 func init() {
 	flag.StringVar(&listenAddress, "web.listen-address", ":9114", "Address to listen on")
 	flag.StringVar(&metricsPath, "web.telemetry-path", "/metrics", "Path under which to expose metrics.")
+	flag.BoolVar(&migProcess, "mig.process", false, "Enumerate Multi-Instance GPU (MIG) instances and report per-instance metrics")
+	flag.BoolVar(&migUseUUID, "mig.use-uuid", false, "Label MIG instances by their mig_uuid instead of gpu_instance_id/compute_instance_id (requires -mig.process)")
+	flag.StringVar(&configFile, "config.file", "", "Path to a JSON config controlling metric/device exclusion and PCI info tagging")
 	flag.Parse()
 }
 
 func main() {
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		log.Fatalf("Failed loading config: %s\n", err.Error())
+	}
+	addPCIInfoTag = cfg.AddPCIInfoTag
+
+	// A missing driver/library isn't fatal: we still want to serve
+	// nvml_up=0 rather than refuse to start, e.g. on a host with no GPU or
+	// a binary built with -tags nocgo.
+	nvmlAvailable := true
 	if err := InitNVML(); err != nil {
-		log.Fatalf("Failed initializing exporter: %s\n", err.Error())
+		log.Printf("NVML unavailable, serving nvml_up=0: %s\n", err.Error())
+		nvmlAvailable = false
+	} else {
+		defer ShutdownNVML()
 	}
-	defer ShutdownNVML()
 
 	landingPageHTML := []byte(fmt.Sprintf(`<html>
              <head><title>NVML Exporter</title></head>
@@ -205,7 +589,7 @@ func main() {
              </html>`, metricsPath))
 
 	log.Printf("Starting NVML Exporter Server: %s\n", listenAddress)
-	exporter, err := NewExporter()
+	exporter, err := NewExporter(cfg, nvmlAvailable)
 	if err != nil {
 		log.Fatalf("Failed initializing exporter: %s\n", err.Error())
 	}
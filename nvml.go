@@ -1,8 +1,11 @@
+//go:build !nocgo
+// +build !nocgo
+
 package main
 
 /*
 #cgo CFLAGS: -I/usr/local/cuda-8.0/include
-#cgo LDFLAGS: -lnvidia-ml -L/usr/lib/nvidia-367
+#cgo LDFLAGS: -ldl
 
 #include "bridge.h"
 */
@@ -13,34 +16,76 @@ import (
 	"unsafe"
 )
 
-var (
-	errNoErrorString = errors.New("nvml: expected an error from driver but got nothing")
-	errNoError       = errors.New("nvml: getGoError called on a successful API call")
-)
+var errNoError = errors.New("nvml: getGoError called on a successful API call")
 
-func getGoError(result C.nvmlReturn_t) (err error) {
-	var errString *C.char
+// NVMLError wraps an nvmlReturn_t code alongside the driver's own message,
+// so callers can distinguish specific failure modes (a driver reset, a GPU
+// falling off the bus) from an ordinary query failure.
+type NVMLError struct {
+	Code int
+	msg  string
+}
+
+func (e *NVMLError) Error() string { return e.msg }
 
+func getGoError(result C.nvmlReturn_t) (err error) {
 	if result == C.NVML_SUCCESS {
 		err = errNoError
 		return
 	}
 
-	if errString = C.nvmlErrorString(result); err != nil {
-		err = errNoErrorString
-		return
+	err = &NVMLError{
+		Code: int(result),
+		msg:  fmt.Sprintf("nvml: %s", C.GoString(C.nvmlErrorString(result))),
 	}
-
-	err = fmt.Errorf("nvml: %s", C.GoString(errString))
 	return
 }
 
+// nvmlNeedsReinit reports whether err indicates NVML's process-wide state is
+// stale and the caller should tear down and re-initialize it before trying
+// again, as happens after a driver reset (nvidia-smi -r) or a GPU dropping
+// off the bus.
+func nvmlNeedsReinit(err error) bool {
+	nerr, ok := err.(*NVMLError)
+	if !ok {
+		return false
+	}
+	return nerr.Code == int(C.NVML_ERROR_UNINITIALIZED) || nerr.Code == int(C.NVML_ERROR_GPU_IS_LOST)
+}
+
+// nvmlNotSupported reports whether err is NVML's way of saying the device
+// doesn't implement the queried feature at all, as opposed to a real query
+// failure. MIG, for instance, returns this for every non-MIG-capable GPU.
+func nvmlNotSupported(err error) bool {
+	nerr, ok := err.(*NVMLError)
+	if !ok {
+		return false
+	}
+	return nerr.Code == int(C.NVML_ERROR_NOT_SUPPORTED)
+}
+
 // Device describes the NVIDIA GPU device attached to the host
 type Device struct {
 	DeviceName string
 	DeviceUUID string
 	d          C.nvmlDevice_t
 	i          int
+
+	// MIGEnabled reports whether Multi-Instance GPU mode is currently active
+	// on this device. Only ever set on parent devices.
+	MIGEnabled bool
+	// MIGDevices holds the enumerated MIG instances when MIGEnabled is true.
+	MIGDevices []Device
+
+	// GPUInstanceID and ComputeInstanceID identify this device within its
+	// parent when it is a MIG instance, and are left at their zero value
+	// otherwise.
+	GPUInstanceID     int
+	ComputeInstanceID int
+
+	// PCIBusID is only populated when addPCIInfoTag is set, since querying it
+	// for every device on every call to GetDevices is otherwise wasted work.
+	PCIBusID string
 }
 
 // NVMLMemory contains information about the memory allocation of a device
@@ -126,6 +171,12 @@ func (s *Device) GetPowerUsage() (usage int, err error) {
 	return
 }
 
+// GetFanSpeed returns the Device's fan speed as a percentage of its maximum
+func (s *Device) GetFanSpeed() (speed int, err error) {
+	speed, err = s.callGetIntFunc(C.getNvmlIntProperty(C.nvmlDeviceGetFanSpeed))
+	return
+}
+
 // GetTemperature returns the Device's temperature in Farenheit and celsius
 func (s *Device) GetTemperature() (tempF, tempC int, err error) {
 	var tempc C.uint
@@ -153,8 +204,311 @@ func (s *Device) GetMemoryInfo() (memInfo NVMLMemory, err error) {
 	return
 }
 
-// InitNVML initializes NVML
+// GetPciBusID returns the device's PCI bus ID, e.g. "0000:01:00.0"
+func (s *Device) GetPciBusID() (busID string, err error) {
+	var info C.nvmlPciInfo_t
+	if result := C.nvmlDeviceGetPciInfo(s.d, &info); result != C.NVML_SUCCESS {
+		err = getGoError(result)
+		return
+	}
+	busID = C.GoString(&info.busId[0])
+	return
+}
+
+// GetMigMode reports whether Multi-Instance GPU mode is currently enabled on the device
+func (s *Device) GetMigMode() (enabled bool, err error) {
+	var current, pending C.uint
+
+	if result := C.bridge_get_mig_mode(s.d, &current, &pending); result != C.NVML_SUCCESS {
+		err = getGoError(result)
+		return
+	}
+
+	enabled = current == C.NVML_DEVICE_MIG_ENABLE
+	return
+}
+
+// GetMaxMigDeviceCount returns the number of MIG device slots supported by this device
+func (s *Device) GetMaxMigDeviceCount() (count int, err error) {
+	count, err = s.callGetIntFunc(C.getNvmlIntProperty(C.nvmlDeviceGetMaxMigDeviceCount))
+	return
+}
+
+// GetMigDeviceHandleByIndex acquires the handle of one of this device's MIG instances, based on its index.
+func (s *Device) GetMigDeviceHandleByIndex(idx int) (migDevice C.nvmlDevice_t, err error) {
+	if result := C.nvmlDeviceGetMigDeviceHandleByIndex(s.d, C.uint(idx), &migDevice); result != C.NVML_SUCCESS {
+		err = getGoError(result)
+		return
+	}
+	return
+}
+
+// GetGpuInstanceID returns the GPU instance ID of a MIG device
+func (s *Device) GetGpuInstanceID() (id int, err error) {
+	id, err = s.callGetIntFunc(C.getNvmlIntProperty(C.nvmlDeviceGetGpuInstanceId))
+	return
+}
+
+// GetComputeInstanceID returns the compute instance ID of a MIG device
+func (s *Device) GetComputeInstanceID() (id int, err error) {
+	id, err = s.callGetIntFunc(C.getNvmlIntProperty(C.nvmlDeviceGetComputeInstanceId))
+	return
+}
+
+// getMigDevices enumerates and describes all MIG instances carved out of this device
+func (s *Device) getMigDevices() (migDevices []Device, err error) {
+	maxCount, err := s.GetMaxMigDeviceCount()
+	if err != nil {
+		return
+	}
+
+	for i := 0; i < maxCount; i++ {
+		var nvdev C.nvmlDevice_t
+		if nvdev, err = s.GetMigDeviceHandleByIndex(i); err != nil {
+			// Not every slot up to maxCount is populated; NVML reports
+			// NVML_ERROR_NOT_FOUND for the ones that aren't in use.
+			err = nil
+			continue
+		}
+
+		var migDev Device
+		if migDev, err = newDevice(nvdev, i); err != nil {
+			return
+		}
+
+		if migDev.GPUInstanceID, err = migDev.GetGpuInstanceID(); err != nil {
+			return
+		}
+		if migDev.ComputeInstanceID, err = migDev.GetComputeInstanceID(); err != nil {
+			return
+		}
+
+		migDevices = append(migDevices, migDev)
+	}
+
+	return
+}
+
+// maxNvLinks bounds how many NVLink indices GetNvLinkState/GetNvLinkUtilizationCounter
+// will probe. NVML has no "link count" query; callers are expected to treat
+// NVML_ERROR_NOT_SUPPORTED on higher indices as "no such link" rather than a fatal error.
+const maxNvLinks = 6
+
+// NvLinkUtilization holds the RX/TX byte counters accumulated for one NVLink
+type NvLinkUtilization struct {
+	RxBytes uint64
+	TxBytes uint64
+}
+
+// GetNvLinkState reports whether the given NVLink index is active on this device
+func (s *Device) GetNvLinkState(link int) (active bool, err error) {
+	var state C.nvmlEnableState_t
+	if result := C.nvmlDeviceGetNvLinkState(s.d, C.uint(link), &state); result != C.NVML_SUCCESS {
+		err = getGoError(result)
+		return
+	}
+	active = state == C.NVML_FEATURE_ENABLED
+	return
+}
+
+// GetNvLinkUtilizationCounter returns the RX/TX byte counts accumulated on counter set 0 of the given NVLink
+func (s *Device) GetNvLinkUtilizationCounter(link int) (util NvLinkUtilization, err error) {
+	var rx, tx C.ulonglong
+	if result := C.nvmlDeviceGetNvLinkUtilizationCounter(s.d, C.uint(link), 0, &rx, &tx); result != C.NVML_SUCCESS {
+		err = getGoError(result)
+		return
+	}
+	util.RxBytes = uint64(rx)
+	util.TxBytes = uint64(tx)
+	return
+}
+
+// PCIeThroughput holds a device's current PCIe TX/RX throughput, in KB/s
+type PCIeThroughput struct {
+	TxKB uint64
+	RxKB uint64
+}
+
+// GetPcieThroughput returns the device's current PCIe TX/RX throughput
+func (s *Device) GetPcieThroughput() (t PCIeThroughput, err error) {
+	var tx, rx C.uint
+
+	if result := C.nvmlDeviceGetPcieThroughput(s.d, C.NVML_PCIE_UTIL_TX_BYTES, &tx); result != C.NVML_SUCCESS {
+		err = getGoError(result)
+		return
+	}
+	if result := C.nvmlDeviceGetPcieThroughput(s.d, C.NVML_PCIE_UTIL_RX_BYTES, &rx); result != C.NVML_SUCCESS {
+		err = getGoError(result)
+		return
+	}
+
+	t.TxKB = uint64(tx)
+	t.RxKB = uint64(rx)
+	return
+}
+
+// ClockSpeeds holds a device's current graphics, SM, and memory clock speeds, in MHz
+type ClockSpeeds struct {
+	Graphics int
+	SM       int
+	Memory   int
+}
+
+// GetClockSpeeds returns the device's current graphics, SM, and memory clock speeds
+func (s *Device) GetClockSpeeds() (c ClockSpeeds, err error) {
+	var graphics, sm, mem C.uint
+
+	if result := C.nvmlDeviceGetClockInfo(s.d, C.NVML_CLOCK_GRAPHICS, &graphics); result != C.NVML_SUCCESS {
+		err = getGoError(result)
+		return
+	}
+	if result := C.nvmlDeviceGetClockInfo(s.d, C.NVML_CLOCK_SM, &sm); result != C.NVML_SUCCESS {
+		err = getGoError(result)
+		return
+	}
+	if result := C.nvmlDeviceGetClockInfo(s.d, C.NVML_CLOCK_MEM, &mem); result != C.NVML_SUCCESS {
+		err = getGoError(result)
+		return
+	}
+
+	c.Graphics = int(graphics)
+	c.SM = int(sm)
+	c.Memory = int(mem)
+	return
+}
+
+// EccErrorCounts holds corrected/uncorrected ECC error counts, split into
+// volatile (since the last driver load) and aggregate (lifetime) counters
+type EccErrorCounts struct {
+	CorrectedVolatile    uint64
+	UncorrectedVolatile  uint64
+	CorrectedAggregate   uint64
+	UncorrectedAggregate uint64
+}
+
+func (s *Device) totalEccErrors(errType C.nvmlMemoryErrorType_t, counterType C.nvmlEccCounterType_t) (count uint64, err error) {
+	var c C.ulonglong
+	if result := C.nvmlDeviceGetTotalEccErrors(s.d, errType, counterType, &c); result != C.NVML_SUCCESS {
+		err = getGoError(result)
+		return
+	}
+	count = uint64(c)
+	return
+}
+
+// GetEccErrors returns the device's corrected/uncorrected, volatile/aggregate ECC error counts
+func (s *Device) GetEccErrors() (counts EccErrorCounts, err error) {
+	if counts.CorrectedVolatile, err = s.totalEccErrors(C.NVML_MEMORY_ERROR_TYPE_CORRECTED, C.NVML_VOLATILE_ECC); err != nil {
+		return
+	}
+	if counts.UncorrectedVolatile, err = s.totalEccErrors(C.NVML_MEMORY_ERROR_TYPE_UNCORRECTED, C.NVML_VOLATILE_ECC); err != nil {
+		return
+	}
+	if counts.CorrectedAggregate, err = s.totalEccErrors(C.NVML_MEMORY_ERROR_TYPE_CORRECTED, C.NVML_AGGREGATE_ECC); err != nil {
+		return
+	}
+	if counts.UncorrectedAggregate, err = s.totalEccErrors(C.NVML_MEMORY_ERROR_TYPE_UNCORRECTED, C.NVML_AGGREGATE_ECC); err != nil {
+		return
+	}
+	return
+}
+
+// GetPerformanceState returns the device's current P-state, where 0 is the highest performance state
+func (s *Device) GetPerformanceState() (state int, err error) {
+	var pstate C.nvmlPstates_t
+	if result := C.nvmlDeviceGetPerformanceState(s.d, &pstate); result != C.NVML_SUCCESS {
+		err = getGoError(result)
+		return
+	}
+	state = int(pstate)
+	return
+}
+
+// GetPowerManagementLimit returns the power limit, in watts, that the driver enforces on this device
+func (s *Device) GetPowerManagementLimit() (limit int, err error) {
+	limit, err = s.callGetIntFunc(C.getNvmlIntProperty(C.nvmlDeviceGetPowerManagementLimit))
+	// nvmlDeviceGetPowerManagementLimit returns milliwatts.. convert to watts
+	limit = limit / 1000
+	return
+}
+
+// ProcessInfo describes one process with an active context on a device
+type ProcessInfo struct {
+	PID        uint32
+	Name       string
+	UsedMemory uint64
+}
+
+func (s *Device) runningProcesses(f func(d C.nvmlDevice_t, count *C.uint, infos *C.nvmlProcessInfo_t) C.nvmlReturn_t) (procs []ProcessInfo, err error) {
+	var count C.uint
+
+	if result := f(s.d, &count, nil); result != C.NVML_SUCCESS && result != C.NVML_ERROR_INSUFFICIENT_SIZE {
+		err = getGoError(result)
+		return
+	}
+	if count == 0 {
+		return
+	}
+
+	infos := make([]C.nvmlProcessInfo_t, count)
+	if result := f(s.d, &count, &infos[0]); result != C.NVML_SUCCESS {
+		err = getGoError(result)
+		return
+	}
+
+	procs = make([]ProcessInfo, 0, count)
+	for i := 0; i < int(count); i++ {
+		name, nameErr := getProcessName(uint32(infos[i].pid))
+		if nameErr != nil {
+			name = "unknown"
+		}
+		procs = append(procs, ProcessInfo{
+			PID:        uint32(infos[i].pid),
+			Name:       name,
+			UsedMemory: uint64(infos[i].usedGpuMemory),
+		})
+	}
+	return
+}
+
+// GetComputeRunningProcesses returns the compute processes with an active context on this device
+func (s *Device) GetComputeRunningProcesses() ([]ProcessInfo, error) {
+	return s.runningProcesses(func(d C.nvmlDevice_t, count *C.uint, infos *C.nvmlProcessInfo_t) C.nvmlReturn_t {
+		return C.nvmlDeviceGetComputeRunningProcesses(d, count, infos)
+	})
+}
+
+// GetGraphicsRunningProcesses returns the graphics processes with an active context on this device
+func (s *Device) GetGraphicsRunningProcesses() ([]ProcessInfo, error) {
+	return s.runningProcesses(func(d C.nvmlDevice_t, count *C.uint, infos *C.nvmlProcessInfo_t) C.nvmlReturn_t {
+		return C.nvmlDeviceGetGraphicsRunningProcesses(d, count, infos)
+	})
+}
+
+// getProcessName resolves a PID to the process name NVML associates with it
+func getProcessName(pid uint32) (name string, err error) {
+	buf := make([]byte, 256)
+	cs := C.CString(string(buf))
+	defer C.free(unsafe.Pointer(cs))
+
+	if result := C.nvmlSystemGetProcessName(C.uint(pid), cs, C.uint(len(buf))); result != C.NVML_SUCCESS {
+		err = getGoError(result)
+		return
+	}
+
+	name = C.GoString(cs)
+	return
+}
+
+// InitNVML locates libnvidia-ml.so.1 with dlopen and resolves the NVML entry
+// points this package calls against it, then initializes NVML. It returns a
+// descriptive error rather than crashing when the driver library isn't
+// installed, so callers can run with NVML disabled instead of aborting.
 func InitNVML() (err error) {
+	if result := C.bridge_init(); result != C.NVML_SUCCESS {
+		err = getGoError(result)
+		return
+	}
 	if result := C.nvmlInit(); result != C.NVML_SUCCESS {
 		err = getGoError(result)
 		return
@@ -212,6 +566,33 @@ func GetDevices() (devices []Device, err error) {
 		if devices[i], err = newDevice(nvdev, i); err != nil {
 			return
 		}
+
+		if addPCIInfoTag {
+			if devices[i].PCIBusID, err = devices[i].GetPciBusID(); err != nil {
+				return
+			}
+		}
+
+		if !migProcess {
+			continue
+		}
+
+		if devices[i].MIGEnabled, err = devices[i].GetMigMode(); err != nil {
+			if nvmlNotSupported(err) {
+				// Most GPUs aren't MIG-capable; leave MIGEnabled false
+				// instead of aborting enumeration for every device.
+				devices[i].MIGEnabled = false
+				err = nil
+			} else {
+				return
+			}
+		}
+
+		if devices[i].MIGEnabled {
+			if devices[i].MIGDevices, err = devices[i].getMigDevices(); err != nil {
+				return
+			}
+		}
 	}
 
 	return
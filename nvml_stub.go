@@ -0,0 +1,135 @@
+//go:build nocgo
+// +build nocgo
+
+package main
+
+import "errors"
+
+// This file backs the -nocgo build tag, which lets the exporter be built
+// without a C compiler or the NVML headers at all. Every exported symbol
+// here mirrors nvml.go's surface so nvidia_exporter.go and config.go don't
+// need to know which build they're in; every call simply reports that NVML
+// is unavailable, and main() serves nvml_up=0 instead of refusing to start.
+
+var errNVMLDisabled = errors.New("nvml: built with the nocgo tag, NVML support is disabled")
+
+// Device describes the NVIDIA GPU device attached to the host. This build
+// never populates anything beyond the zero value.
+type Device struct {
+	DeviceName string
+	DeviceUUID string
+
+	MIGEnabled bool
+	MIGDevices []Device
+
+	GPUInstanceID     int
+	ComputeInstanceID int
+
+	PCIBusID string
+}
+
+// NVMLMemory contains information about the memory allocation of a device
+type NVMLMemory struct {
+	Free  int64
+	Total int64
+	Used  int64
+}
+
+// NvLinkUtilization holds the RX/TX byte counters accumulated for one NVLink
+type NvLinkUtilization struct {
+	RxBytes uint64
+	TxBytes uint64
+}
+
+// PCIeThroughput holds a device's current PCIe TX/RX throughput, in KB/s
+type PCIeThroughput struct {
+	TxKB uint64
+	RxKB uint64
+}
+
+// ClockSpeeds holds a device's current graphics, SM, and memory clock speeds, in MHz
+type ClockSpeeds struct {
+	Graphics int
+	SM       int
+	Memory   int
+}
+
+// EccErrorCounts holds corrected/uncorrected ECC error counts, split into
+// volatile (since the last driver load) and aggregate (lifetime) counters
+type EccErrorCounts struct {
+	CorrectedVolatile    uint64
+	UncorrectedVolatile  uint64
+	CorrectedAggregate   uint64
+	UncorrectedAggregate uint64
+}
+
+// ProcessInfo describes one process with an active context on a device
+type ProcessInfo struct {
+	PID        uint32
+	Name       string
+	UsedMemory uint64
+}
+
+// maxNvLinks mirrors nvml.go's constant so shared callers compile unchanged.
+const maxNvLinks = 6
+
+func (s *Device) GetUtilization() (gpu, memory int, err error) { return 0, 0, errNVMLDisabled }
+
+func (s *Device) GetPowerUsage() (usage int, err error) { return 0, errNVMLDisabled }
+
+func (s *Device) GetFanSpeed() (speed int, err error) { return 0, errNVMLDisabled }
+
+func (s *Device) GetTemperature() (tempF, tempC int, err error) { return 0, 0, errNVMLDisabled }
+
+func (s *Device) GetMemoryInfo() (memInfo NVMLMemory, err error) {
+	return NVMLMemory{}, errNVMLDisabled
+}
+
+func (s *Device) GetPciBusID() (busID string, err error) { return "", errNVMLDisabled }
+
+func (s *Device) GetMigMode() (enabled bool, err error) { return false, errNVMLDisabled }
+
+func (s *Device) GetNvLinkState(link int) (active bool, err error) { return false, errNVMLDisabled }
+
+func (s *Device) GetNvLinkUtilizationCounter(link int) (util NvLinkUtilization, err error) {
+	return NvLinkUtilization{}, errNVMLDisabled
+}
+
+func (s *Device) GetPcieThroughput() (t PCIeThroughput, err error) {
+	return PCIeThroughput{}, errNVMLDisabled
+}
+
+func (s *Device) GetClockSpeeds() (c ClockSpeeds, err error) { return ClockSpeeds{}, errNVMLDisabled }
+
+func (s *Device) GetEccErrors() (counts EccErrorCounts, err error) {
+	return EccErrorCounts{}, errNVMLDisabled
+}
+
+func (s *Device) GetPerformanceState() (state int, err error) { return 0, errNVMLDisabled }
+
+func (s *Device) GetPowerManagementLimit() (limit int, err error) { return 0, errNVMLDisabled }
+
+func (s *Device) GetComputeRunningProcesses() ([]ProcessInfo, error) { return nil, errNVMLDisabled }
+
+func (s *Device) GetGpuInstanceID() (id int, err error) { return 0, errNVMLDisabled }
+
+func (s *Device) GetComputeInstanceID() (id int, err error) { return 0, errNVMLDisabled }
+
+func (s *Device) GetGraphicsRunningProcesses() ([]ProcessInfo, error) { return nil, errNVMLDisabled }
+
+// InitNVML always fails in a -nocgo build; main() treats that as "run with
+// NVML disabled" rather than a fatal error.
+func InitNVML() error { return errNVMLDisabled }
+
+// ShutdownNVML is a no-op: InitNVML never succeeds, so there's nothing to tear down.
+func ShutdownNVML() error { return nil }
+
+// GetDeviceCount always reports zero devices in a -nocgo build.
+func GetDeviceCount() (count int, err error) { return 0, errNVMLDisabled }
+
+// GetDevices always reports no devices in a -nocgo build.
+func GetDevices() (devices []Device, err error) { return nil, nil }
+
+// nvmlNeedsReinit always reports false: a -nocgo build never has live NVML
+// state to go stale, since InitNVML never succeeds.
+func nvmlNeedsReinit(err error) bool { return false }